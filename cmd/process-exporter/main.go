@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
@@ -15,6 +18,7 @@ import (
 	common "github.com/ncabatoff/process-exporter"
 	"github.com/ncabatoff/process-exporter/collector"
 	"github.com/ncabatoff/process-exporter/config"
+	"github.com/ncabatoff/process-exporter/pusher"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/promlog"
@@ -140,6 +144,24 @@ func (nmr *nameMapperRegex) MatchAndName(nacl common.ProcAttributes) (bool, stri
 	return false, ""
 }
 
+// newJoinSource builds the collector.JoinSource selected by a config
+// file's `join:` section; config.JoinConfig.validate has already ensured
+// exactly one of Static/File/URL is set.
+func newJoinSource(jc *config.JoinConfig) (collector.JoinSource, error) {
+	switch {
+	case jc.Static != nil:
+		targets := make([]collector.JoinTarget, len(jc.Static))
+		for i, t := range jc.Static {
+			targets[i] = collector.JoinTarget{ContainerID: t.ContainerID, Labels: t.Labels}
+		}
+		return collector.NewStaticJoinSource(targets), nil
+	case jc.File != "":
+		return collector.NewFileJoinSource(jc.File, jc.RefreshInterval)
+	default:
+		return collector.NewHTTPJoinSource(jc.URL, jc.RefreshInterval)
+	}
+}
+
 func init() {
 	prometheus.MustRegister(promVersion.NewCollector("process_exporter"))
 }
@@ -155,10 +177,17 @@ func main() {
 		children          = kingpin.Flag("children", "if a proc is tracked, track with it any children that aren't part of their own group").Default("true").Bool()
 		threads           = kingpin.Flag("threads", "report on per-threadname metrics as well").Default("true").Bool()
 		smaps             = kingpin.Flag("gather-smaps", "gather metrics from smaps file, which contains proportional resident memory size").Default("true").Bool()
+		gatherIO          = kingpin.Flag("gather-io", "gather per-process io and scheduler-stall metrics from /proc/<pid>/io and /proc/<pid>/schedstat").Default("false").Bool()
 		man               = kingpin.Flag("man", "path to YAML config file").Default("false").Bool()
 		configPath        = kingpin.Flag("config.path", "path to YAML config file").Default("").String()
 		recheck           = kingpin.Flag("recheck", "recheck process names on each scrape").Default("false").Bool()
 		debug             = kingpin.Flag("debug", "log debugging information to stdout").Default("false").Bool()
+		cgroupIDRegex     = kingpin.Flag("cgroup.id-regex", "regex with one capture group applied to each line of /proc/<pid>/cgroup; the capture becomes the cgroup_id label").Default("").String()
+		enableLifecycle   = kingpin.Flag("web.enable-lifecycle", "enable the /-/reload HTTP endpoint, mirroring Prometheus's flag of the same name; SIGHUP always reloads regardless").Default("false").Bool()
+		otlpEndpoint      = kingpin.Flag("otlp.endpoint", "OTLP collector endpoint (host:port); if set, metrics are pushed here in addition to being served for scraping").Default("").String()
+		otlpProtocol      = kingpin.Flag("otlp.protocol", "OTLP wire protocol: grpc or http/protobuf").Default(string(pusher.ProtocolGRPC)).String()
+		otlpInterval      = kingpin.Flag("otlp.interval", "how often to push metrics to the OTLP endpoint").Default("15s").Duration()
+		otlpHeaders       = kingpin.Flag("otlp.headers", "extra headers to send with every OTLP export, as key=value").StringMap()
 		// showVersion       = kingpin.Flag("version", "print version information and exit").Default("false").Bool()
 	)
 	kingpin.Version(promVersion.Print(version))
@@ -179,6 +208,19 @@ func main() {
 	}
 
 	var matchnamer common.MatchNamer
+	var cgroupIDPattern *regexp.Regexp
+	var joinSource collector.JoinSource
+
+	if *cgroupIDRegex != "" {
+		re, err := regexp.Compile(*cgroupIDRegex)
+		if err != nil {
+			log.Fatalf("error compiling -cgroup.id-regex %q: %v", *cgroupIDRegex, err)
+		}
+		if re.NumSubexp() < 1 {
+			log.Fatalf("-cgroup.id-regex must have a capture group")
+		}
+		cgroupIDPattern = re
+	}
 
 	if *configPath != "" {
 		if *nameMapping != "" || *procNames != "" {
@@ -194,6 +236,18 @@ func main() {
 		if *debug {
 			log.Printf("using config matchnamer: %v", cfg.MatchNamers)
 		}
+		if cgroupIDPattern == nil {
+			cgroupIDPattern = cfg.CgroupIDRegex
+		}
+		if cfg.Join != nil {
+			joinSource, err = newJoinSource(cfg.Join)
+			if err != nil {
+				log.Fatalf("error configuring join: %v", err)
+			}
+		}
+		if cfg.GatherIO {
+			*gatherIO = true
+		}
 	} else {
 		namemapper, err := parseNameMapper(*nameMapping)
 		if err != nil {
@@ -219,13 +273,16 @@ func main() {
 
 	pc, err := collector.NewProcessCollector(
 		collector.ProcessCollectorOption{
-			ProcFSPath:  *procfsPath,
-			Children:    *children,
-			Threads:     *threads,
-			GatherSMaps: *smaps,
-			Namer:       matchnamer,
-			Recheck:     *recheck,
-			Debug:       *debug,
+			ProcFSPath:    *procfsPath,
+			Children:      *children,
+			Threads:       *threads,
+			GatherSMaps:   *smaps,
+			Namer:         matchnamer,
+			Recheck:       *recheck,
+			Debug:         *debug,
+			CgroupIDRegex: cgroupIDPattern,
+			Join:          joinSource,
+			GatherIO:      *gatherIO,
 		},
 	)
 	if err != nil {
@@ -234,6 +291,26 @@ func main() {
 
 	prometheus.MustRegister(pc)
 
+	if *otlpEndpoint != "" {
+		push, err := pusher.New(pusher.Config{
+			Endpoint: *otlpEndpoint,
+			Protocol: pusher.Protocol(*otlpProtocol),
+			Interval: *otlpInterval,
+			Headers:  *otlpHeaders,
+		})
+		if err != nil {
+			log.Fatalf("Error initializing OTLP pusher: %v", err)
+		}
+
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = ""
+		}
+
+		log.Printf("Pushing metrics to %s every %s via %s", *otlpEndpoint, *otlpInterval, *otlpProtocol)
+		go push.Run(context.Background(), prometheus.DefaultGatherer, hostname)
+	}
+
 	if *onceToStdoutDelay != 0 {
 		// We throw away the first result because that first collection primes the pump, and
 		// otherwise we won't see our counter metrics.  This is specific to the implementation
@@ -245,6 +322,42 @@ func main() {
 		return
 	}
 
+	reload := func() error {
+		if *configPath == "" {
+			return fmt.Errorf("reload requested but -config.path isn't set")
+		}
+		if err := pc.Reload(*configPath); err != nil {
+			log.Printf("Error reloading config: %v", err)
+			return err
+		}
+		log.Printf("Reloaded config from %s", *configPath)
+		return nil
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reload()
+		}
+	}()
+
+	http.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+		if !*enableLifecycle {
+			http.Error(w, "Lifecycle API is not enabled; pass --web.enable-lifecycle to enable it.", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "this endpoint requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reload(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
 	http.Handle(*metricsPath, promhttp.Handler())
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// readCgroups returns the raw, non-empty lines of /proc/<pid>/cgroup, e.g.
+//
+//	0::/user.slice/user-1000.slice/session-2.scope          (cgroups v2)
+//	4:memory:/kubepods/burstable/pod123/abcdef0123           (cgroups v1)
+//
+// A missing file (kernel built without cgroups) is not an error; it just
+// yields no lines.
+func readCgroups(procfsPath string, pid int) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(procfsPath, strconv.Itoa(pid), "cgroup"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// cgroupID applies re to each cgroup line, looking for one capture group,
+// and returns the first match found.  On cgroups v2 there's a single line
+// to check; on v1 there's one line per controller hierarchy, and the first
+// one whose path matches re wins.
+func cgroupID(re *regexp.Regexp, cgroups []string) string {
+	for _, line := range cgroups {
+		if m := re.FindStringSubmatch(line); len(m) > 1 {
+			return m[1]
+		}
+	}
+	return ""
+}
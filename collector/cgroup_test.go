@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func writeFakeProc(t *testing.T, procfsPath string, pid int, comm, cmdline string, cgroup []string) {
+	t.Helper()
+	dir := filepath.Join(procfsPath, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stat := "1 (" + comm + ") S 0 1 1 0 -1 0 0 0 0 0 100 50 0 0 20 0 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0"
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cgroup != nil {
+		content := ""
+		for _, line := range cgroup {
+			content += line + "\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCgroupID_V2(t *testing.T) {
+	re := regexp.MustCompile(`^0::(.*)$`)
+	lines := []string{"0::/system.slice/docker-abc123.scope"}
+	if got := cgroupID(re, lines); got != "/system.slice/docker-abc123.scope" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCgroupID_V1FirstMatchWins(t *testing.T) {
+	re := regexp.MustCompile(`:memory:(/.*)$`)
+	lines := []string{
+		"4:cpu,cpuacct:/kubepods/burstable/pod1/abc",
+		"3:memory:/kubepods/burstable/pod1/abc",
+	}
+	if got := cgroupID(re, lines); got != "/kubepods/burstable/pod1/abc" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCgroupID_NoMatch(t *testing.T) {
+	re := regexp.MustCompile(`^nomatch(/.*)$`)
+	lines := []string{"0::/init.scope"}
+	if got := cgroupID(re, lines); got != "" {
+		t.Errorf("expected empty, got %q", got)
+	}
+}
+
+func TestReadCgroups(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "sleep", "sleep\x00100\x00", []string{"0::/a.scope", "1:cpu:/b.scope"})
+
+	lines, err := readCgroups(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0] != "0::/a.scope" || lines[1] != "1:cpu:/b.scope" {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+}
+
+func TestReadCgroups_Missing(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := readCgroups(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != nil {
+		t.Errorf("expected nil lines for missing cgroup file, got %v", lines)
+	}
+}
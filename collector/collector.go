@@ -0,0 +1,602 @@
+// Package collector implements the prometheus.Collector that walks procfs
+// on every scrape, groups processes according to a common.MatchNamer, and
+// exposes aggregated resource-usage metrics per group.
+package collector
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	common "github.com/ncabatoff/process-exporter"
+	"github.com/ncabatoff/process-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "namedprocess"
+const subsystem = "namegroup"
+
+// ProcessCollectorOption holds the config for NewProcessCollector.
+type ProcessCollectorOption struct {
+	// ProcFSPath is normally "/proc"; override for testing against fixtures.
+	ProcFSPath string
+	// Children tracks subprocesses that aren't part of their own group
+	// under the first ancestor group found.
+	Children bool
+	// Threads, if true, also emits a per-group thread-count metric,
+	// read from /proc/<pid>/stat's num_threads field.
+	Threads bool
+	// GatherSMaps enables reading /proc/<pid>/smaps_rollup for a
+	// per-group proportional resident memory metric.
+	GatherSMaps bool
+	// Namer decides which processes to track and how to group them.
+	Namer common.MatchNamer
+	// Recheck re-evaluates Namer against every process on every scrape,
+	// instead of caching the verdict for the lifetime of the process.
+	Recheck bool
+	Debug   bool
+
+	// CgroupIDRegex, if non-nil, is applied to each line of
+	// /proc/<pid>/cgroup; the first capture group of the first matching
+	// line becomes the process's cgroup ID, exposed as the cgroup_id
+	// label on all namedprocess_namegroup_* metrics alongside groupname.
+	CgroupIDRegex *regexp.Regexp
+
+	// Join, if non-nil, resolves each process's container ID (derived
+	// from its cgroup membership) to a set of externally-supplied
+	// labels -- e.g. pod name and namespace -- which are attached to
+	// that process's metrics alongside groupname and cgroup_id.
+	Join JoinSource
+
+	// GatherIO enables reading /proc/<pid>/io and /proc/<pid>/schedstat;
+	// the former requires extra permission and both add per-scrape cost,
+	// hence being opt-in like GatherSMaps.
+	GatherIO bool
+}
+
+// NamedProcessCollector is a prometheus.Collector that reports aggregated
+// metrics for groups of processes sharing a name assigned by a
+// common.MatchNamer.
+type NamedProcessCollector struct {
+	mu   sync.Mutex
+	opts ProcessCollectorOption
+
+	scrapeErrors prometheus.Counter
+	numProcs     *prometheus.Desc
+	numThreads   *prometheus.Desc
+	cpuSecs      *prometheus.Desc
+	memBytes     *prometheus.Desc
+	ioBytes      *prometheus.Desc
+	ioOps        *prometheus.Desc
+	schedSecs    *prometheus.Desc
+	schedSwitch  *prometheus.Desc
+
+	configReloadSuccess   prometheus.Gauge
+	configReloadTimestamp prometheus.Gauge
+
+	// namer is read on every scrape and swapped out wholesale by Reload,
+	// so a scrape in progress never sees a half-updated MatchNamer.
+	namer atomic.Pointer[common.MatchNamer]
+
+	// usernameCache avoids a user.LookupId syscall per process per scrape.
+	usernameCache map[string]string
+
+	// nameCache holds the MatchAndName verdict for each tracked pid, so
+	// that unless opts.Recheck is set, a process's group is decided once
+	// (on the scrape it's first seen) and doesn't change for its
+	// lifetime even if the Namer would now decide differently. Entries
+	// are dropped once their pid no longer shows up in procfs, and the
+	// whole cache is dropped by Reload since a new Namer may decide
+	// differently.
+	nameCache map[int]matchResult
+
+	// joinLabels is the label names contributed by opts.Join, fixed at
+	// construction time since prometheus.Desc's label set can't vary.
+	joinLabels []string
+}
+
+// matchResult is a cached MatchAndName verdict, keyed by pid.
+type matchResult struct {
+	ok   bool
+	name string
+}
+
+// NewProcessCollector creates a NamedProcessCollector using the given options.
+func NewProcessCollector(opts ProcessCollectorOption) (*NamedProcessCollector, error) {
+	if opts.Namer == nil {
+		return nil, fmt.Errorf("collector: Namer is required")
+	}
+	if opts.ProcFSPath == "" {
+		opts.ProcFSPath = "/proc"
+	}
+
+	var joinLabels []string
+	if opts.Join != nil {
+		joinLabels = append([]string{"container_id"}, opts.Join.LabelNames()...)
+	}
+	baseLabels := append([]string{"groupname", "cgroup_id"}, joinLabels...)
+
+	p := &NamedProcessCollector{
+		opts:          opts,
+		usernameCache: make(map[string]string),
+		nameCache:     make(map[int]matchResult),
+		joinLabels:    joinLabels,
+		scrapeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scrape_errors_total",
+			Help:      "Number of errors while scraping procfs.",
+		}),
+		configReloadSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "process_exporter",
+			Name:      "config_last_reload_successful",
+			Help:      "Whether the last configuration reload attempt succeeded.",
+		}),
+		configReloadTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "process_exporter",
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful configuration reload.",
+		}),
+		numProcs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "num_procs"),
+			"Number of processes in this group.",
+			baseLabels, nil,
+		),
+		numThreads: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "num_threads"),
+			"Number of threads, summed across all procs in the group.",
+			baseLabels, nil,
+		),
+		cpuSecs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "cpu_seconds_total"),
+			"Cpu usage in seconds, summed across all procs in the group.",
+			append(append([]string{}, baseLabels...), "mode"), nil,
+		),
+		memBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "memory_proportional_resident_bytes"),
+			"Proportional resident memory, summed across all procs in the group, as reported by /proc/<pid>/smaps_rollup.",
+			baseLabels, nil,
+		),
+		ioBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "io_bytes_total"),
+			"Bytes read/written, summed across all procs in the group, as reported by /proc/<pid>/io.",
+			append(append([]string{}, baseLabels...), "iomode"), nil,
+		),
+		ioOps: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "io_syscalls_total"),
+			"Number of read/write syscalls issued, summed across all procs in the group.",
+			append(append([]string{}, baseLabels...), "iomode"), nil,
+		),
+		schedSecs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "cpu_sched_seconds_total"),
+			"Time spent running on or waiting for a CPU, summed across all procs in the group, from /proc/<pid>/schedstat.",
+			append(append([]string{}, baseLabels...), "schedmode"), nil,
+		),
+		schedSwitch: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "context_switches_total"),
+			"Number of scheduler context switches, summed across all procs in the group, from /proc/<pid>/schedstat.",
+			baseLabels, nil,
+		),
+	}
+	namer := opts.Namer
+	p.namer.Store(&namer)
+	p.configReloadSuccess.Set(1)
+	p.configReloadTimestamp.Set(float64(time.Now().Unix()))
+
+	return p, nil
+}
+
+// Describe implements prometheus.Collector.
+func (p *NamedProcessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.numProcs
+	ch <- p.cpuSecs
+	if p.opts.Threads {
+		ch <- p.numThreads
+	}
+	if p.opts.GatherSMaps {
+		ch <- p.memBytes
+	}
+	if p.opts.GatherIO {
+		ch <- p.ioBytes
+		ch <- p.ioOps
+		ch <- p.schedSecs
+		ch <- p.schedSwitch
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (p *NamedProcessCollector) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	groups, err := p.scrape()
+	if err != nil {
+		p.scrapeErrors.Inc()
+		if p.opts.Debug {
+			fmt.Fprintf(os.Stderr, "collector: scrape error: %v\n", err)
+		}
+	}
+
+	ch <- p.scrapeErrors
+	ch <- p.configReloadSuccess
+	ch <- p.configReloadTimestamp
+
+	for key, g := range groups {
+		labels := p.labelValues(key)
+		ch <- prometheus.MustNewConstMetric(p.numProcs, prometheus.GaugeValue, float64(g.numProcs), labels...)
+		ch <- prometheus.MustNewConstMetric(p.cpuSecs, prometheus.CounterValue, g.cpuUserSecs, append(append([]string{}, labels...), "user")...)
+		ch <- prometheus.MustNewConstMetric(p.cpuSecs, prometheus.CounterValue, g.cpuSystemSecs, append(append([]string{}, labels...), "system")...)
+
+		if p.opts.Threads {
+			ch <- prometheus.MustNewConstMetric(p.numThreads, prometheus.GaugeValue, float64(g.numThreads), labels...)
+		}
+		if p.opts.GatherSMaps {
+			ch <- prometheus.MustNewConstMetric(p.memBytes, prometheus.GaugeValue, float64(g.memPropResidentBytes), labels...)
+		}
+
+		if !p.opts.GatherIO {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(p.ioBytes, prometheus.CounterValue, float64(g.ioReadBytes), append(append([]string{}, labels...), "read")...)
+		ch <- prometheus.MustNewConstMetric(p.ioBytes, prometheus.CounterValue, float64(g.ioWriteBytes), append(append([]string{}, labels...), "write")...)
+		ch <- prometheus.MustNewConstMetric(p.ioBytes, prometheus.CounterValue, float64(g.ioCancelledWriteBytes), append(append([]string{}, labels...), "cancelled_write")...)
+		ch <- prometheus.MustNewConstMetric(p.ioBytes, prometheus.CounterValue, float64(g.ioRchar), append(append([]string{}, labels...), "rchar")...)
+		ch <- prometheus.MustNewConstMetric(p.ioBytes, prometheus.CounterValue, float64(g.ioWchar), append(append([]string{}, labels...), "wchar")...)
+		ch <- prometheus.MustNewConstMetric(p.ioOps, prometheus.CounterValue, float64(g.ioSyscr), append(append([]string{}, labels...), "read")...)
+		ch <- prometheus.MustNewConstMetric(p.ioOps, prometheus.CounterValue, float64(g.ioSyscw), append(append([]string{}, labels...), "write")...)
+		ch <- prometheus.MustNewConstMetric(p.schedSecs, prometheus.CounterValue, g.schedRunSecs, append(append([]string{}, labels...), "run")...)
+		ch <- prometheus.MustNewConstMetric(p.schedSecs, prometheus.CounterValue, g.schedWaitSecs, append(append([]string{}, labels...), "wait")...)
+		ch <- prometheus.MustNewConstMetric(p.schedSwitch, prometheus.CounterValue, float64(g.schedSwitches), labels...)
+	}
+}
+
+// Reload re-parses the YAML config file at path and, if it's valid, swaps
+// in its MatchNamer atomically so the very next Collect uses it -- no
+// restart required. It always updates the config_last_reload_successful
+// gauge; config_last_reload_success_timestamp_seconds is updated only when
+// the reload succeeds.
+func (p *NamedProcessCollector) Reload(path string) error {
+	cfg, err := config.ReadFile(path, p.opts.Debug)
+	if err != nil {
+		p.configReloadSuccess.Set(0)
+		return fmt.Errorf("collector: error reloading config %q: %v", path, err)
+	}
+
+	namer := cfg.MatchNamers
+	p.namer.Store(&namer)
+
+	// A reload means the rules for deciding group membership may have
+	// changed, so forget every cached Recheck=false verdict: the next
+	// scrape re-evaluates every tracked process against the new Namer.
+	p.mu.Lock()
+	p.nameCache = make(map[int]matchResult)
+	p.mu.Unlock()
+
+	p.configReloadSuccess.Set(1)
+	p.configReloadTimestamp.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+// labelValues returns the label values for key in the same order as the
+// Desc label lists built in NewProcessCollector: groupname, cgroup_id,
+// then (if a Join source is configured) container_id and its label names.
+func (p *NamedProcessCollector) labelValues(key groupKey) []string {
+	values := []string{key.name, key.cgroupID}
+	if p.opts.Join == nil {
+		return values
+	}
+
+	values = append(values, key.containerID)
+	joined := p.opts.Join.Labels(key.containerID)
+	for _, name := range p.joinLabels[1:] { // [0] is "container_id", already appended
+		values = append(values, joined[name])
+	}
+	return values
+}
+
+// groupKey identifies one row of aggregated metrics: a group name, plus
+// (when -cgroup.id-regex or a Join source is in use) the cgroup/container
+// ID subdividing it further.
+type groupKey struct {
+	name        string
+	cgroupID    string
+	containerID string
+}
+
+// groupcounts holds the aggregated values for a single groupKey.
+type groupcounts struct {
+	numProcs             int
+	numThreads           int
+	cpuUserSecs          float64
+	cpuSystemSecs        float64
+	memPropResidentBytes uint64
+
+	ioReadBytes           uint64
+	ioWriteBytes          uint64
+	ioCancelledWriteBytes uint64
+	ioRchar               uint64
+	ioWchar               uint64
+	ioSyscr               uint64
+	ioSyscw               uint64
+
+	schedRunSecs  float64
+	schedWaitSecs float64
+	schedSwitches uint64
+}
+
+// procInfo is everything scrape needs about one live pid: its identity for
+// matching/grouping, its per-process counts, and its parent pid so
+// opts.Children can walk the process tree upwards.
+type procInfo struct {
+	attrs  common.ProcAttributes
+	counts proccounts
+	ppid   int
+}
+
+// scrape walks procfs once, grouping every tracked process via the current
+// MatchNamer (swapped out atomically by Reload).
+func (p *NamedProcessCollector) scrape() (map[groupKey]*groupcounts, error) {
+	entries, err := os.ReadDir(p.opts.ProcFSPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", p.opts.ProcFSPath, err)
+	}
+	namer := *p.namer.Load()
+
+	procs := make(map[int]procInfo, len(entries))
+	alive := make(map[int]bool, len(entries))
+	for _, ent := range entries {
+		pid, err := strconv.Atoi(ent.Name())
+		if err != nil {
+			continue
+		}
+		alive[pid] = true
+
+		attrs, counts, ppid, err := p.readProc(pid)
+		if err != nil {
+			// Process may have exited between the readdir and the read;
+			// this is normal and not worth logging.
+			continue
+		}
+		procs[pid] = procInfo{attrs: attrs, counts: counts, ppid: ppid}
+	}
+
+	for pid := range p.nameCache {
+		if !alive[pid] {
+			delete(p.nameCache, pid)
+		}
+	}
+
+	groups := make(map[groupKey]*groupcounts)
+	for pid, info := range procs {
+		ok, name := p.matchAndName(namer, pid, info.attrs)
+		attrs := info.attrs
+		if !ok && p.opts.Children {
+			ok, name, attrs = p.ancestorGroup(namer, procs, info.ppid)
+		}
+		if !ok {
+			continue
+		}
+
+		key := groupKey{name: name}
+		if p.opts.CgroupIDRegex != nil {
+			key.cgroupID = cgroupID(p.opts.CgroupIDRegex, attrs.Cgroups)
+		}
+		if p.opts.Join != nil {
+			key.containerID = ExtractContainerID(attrs.Cgroups)
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &groupcounts{}
+			groups[key] = g
+		}
+		g.numProcs++
+		g.numThreads += info.counts.numThreads
+		g.cpuUserSecs += info.counts.cpuUserSecs
+		g.cpuSystemSecs += info.counts.cpuSystemSecs
+		g.memPropResidentBytes += info.counts.memPropResidentBytes
+		g.ioReadBytes += info.counts.io.readBytes
+		g.ioWriteBytes += info.counts.io.writeBytes
+		g.ioCancelledWriteBytes += info.counts.io.cancelledWriteBytes
+		g.ioRchar += info.counts.io.rchar
+		g.ioWchar += info.counts.io.wchar
+		g.ioSyscr += info.counts.io.syscr
+		g.ioSyscw += info.counts.io.syscw
+		g.schedRunSecs += info.counts.sched.runSecs
+		g.schedWaitSecs += info.counts.sched.waitSecs
+		g.schedSwitches += info.counts.sched.nrSwitch
+	}
+
+	return groups, nil
+}
+
+// matchAndName runs namer against a pid's attrs, caching the verdict for
+// the lifetime of the pid unless opts.Recheck is set.
+func (p *NamedProcessCollector) matchAndName(namer common.MatchNamer, pid int, attrs common.ProcAttributes) (bool, string) {
+	if !p.opts.Recheck {
+		if cached, found := p.nameCache[pid]; found {
+			return cached.ok, cached.name
+		}
+	}
+
+	ok, name := namer.MatchAndName(attrs)
+	if !p.opts.Recheck {
+		p.nameCache[pid] = matchResult{ok: ok, name: name}
+	}
+	return ok, name
+}
+
+// ancestorGroup walks the process tree upwards from ppid looking for the
+// nearest ancestor that's tracked in its own right, for opts.Children.  It
+// returns that ancestor's verdict and attrs (so the caller groups the
+// child alongside it, under the ancestor's cgroup/container identity), or
+// no match at all if the walk runs off the top of the tree (ppid 0) or
+// reaches a pid that's no longer around.
+func (p *NamedProcessCollector) ancestorGroup(namer common.MatchNamer, procs map[int]procInfo, ppid int) (bool, string, common.ProcAttributes) {
+	seen := make(map[int]bool)
+	for pid := ppid; pid > 0 && !seen[pid]; {
+		seen[pid] = true
+		info, found := procs[pid]
+		if !found {
+			break
+		}
+		if ok, name := p.matchAndName(namer, pid, info.attrs); ok {
+			return true, name, info.attrs
+		}
+		pid = info.ppid
+	}
+	return false, "", common.ProcAttributes{}
+}
+
+type proccounts struct {
+	cpuUserSecs          float64
+	cpuSystemSecs        float64
+	numThreads           int
+	memPropResidentBytes uint64
+	io                   iocounts
+	sched                schedcounts
+}
+
+// clockTicks is the kernel's USER_HZ, used to convert /proc/<pid>/stat's
+// utime/stime fields (in clock ticks) to seconds.
+const clockTicks = 100
+
+// readProc reads the portion of /proc/<pid>/* needed to name and account
+// for a single process, plus its ppid for opts.Children.
+func (p *NamedProcessCollector) readProc(pid int) (common.ProcAttributes, proccounts, int, error) {
+	dir := filepath.Join(p.opts.ProcFSPath, strconv.Itoa(pid))
+
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return common.ProcAttributes{}, proccounts{}, 0, err
+	}
+
+	cmdlineRaw, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return common.ProcAttributes{}, proccounts{}, 0, err
+	}
+	var cmdline []string
+	for _, tok := range strings.Split(strings.TrimRight(string(cmdlineRaw), "\x00"), "\x00") {
+		if tok != "" {
+			cmdline = append(cmdline, tok)
+		}
+	}
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return common.ProcAttributes{}, proccounts{}, 0, err
+	}
+	counts, ppid := parseStatCPU(string(stat))
+	if !p.opts.Threads {
+		counts.numThreads = 0
+	}
+
+	if p.opts.GatherSMaps {
+		if mem, err := readSMapsRollupPss(p.opts.ProcFSPath, pid); err == nil {
+			counts.memPropResidentBytes = mem
+		} else if p.opts.Debug {
+			fmt.Fprintf(os.Stderr, "collector: error reading smaps_rollup for pid %d: %v\n", pid, err)
+		}
+	}
+
+	if p.opts.GatherIO {
+		if io, err := readIO(p.opts.ProcFSPath, pid); err == nil {
+			counts.io = io
+		} else if p.opts.Debug {
+			fmt.Fprintf(os.Stderr, "collector: error reading io for pid %d: %v\n", pid, err)
+		}
+		if sched, err := readSchedstat(p.opts.ProcFSPath, pid); err == nil {
+			counts.sched = sched
+		} else if p.opts.Debug {
+			fmt.Fprintf(os.Stderr, "collector: error reading schedstat for pid %d: %v\n", pid, err)
+		}
+	}
+
+	cgroups, err := readCgroups(p.opts.ProcFSPath, pid)
+	if err != nil && p.opts.Debug {
+		fmt.Fprintf(os.Stderr, "collector: error reading cgroups for pid %d: %v\n", pid, err)
+	}
+
+	exe, err := os.Readlink(filepath.Join(dir, "exe"))
+	if err != nil && p.opts.Debug {
+		fmt.Fprintf(os.Stderr, "collector: error reading exe for pid %d: %v\n", pid, err)
+	}
+
+	attrs := common.ProcAttributes{
+		Name:     strings.TrimSpace(string(comm)),
+		Exe:      exe,
+		Cmdline:  cmdline,
+		Cgroups:  cgroups,
+		Username: p.username(dir),
+	}
+
+	return attrs, counts, ppid, nil
+}
+
+// username resolves the owner of the process whose /proc/<pid> dir is dir,
+// falling back to the numeric uid if it can't be looked up by name.
+func (p *NamedProcessCollector) username(dir string) string {
+	status, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return ""
+	}
+
+	uid := ""
+	for _, line := range strings.Split(string(status), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "Uid:" {
+			uid = fields[1]
+			break
+		}
+	}
+	if uid == "" {
+		return ""
+	}
+
+	if name, ok := p.usernameCache[uid]; ok {
+		return name
+	}
+
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	p.usernameCache[uid] = name
+	return name
+}
+
+// parseStatCPU extracts utime/stime (fields 14 and 15), ppid (field 4) and
+// num_threads (field 20) from the contents of /proc/<pid>/stat, returning
+// the ppid separately since it's topology, not an accountable count.  The
+// comm field (2nd, parenthesized) may itself contain spaces or parens, so
+// we split on the last ')' rather than whitespace.
+func parseStatCPU(stat string) (proccounts, int) {
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return proccounts{}, 0
+	}
+	fields := strings.Fields(stat[end+1:])
+	// fields[0] is field 3 (state); ppid is field 4, i.e. fields[1].
+	if len(fields) < 13 {
+		return proccounts{}, 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+
+	var numThreads int
+	if len(fields) > 17 {
+		numThreads, _ = strconv.Atoi(fields[17])
+	}
+
+	return proccounts{
+		cpuUserSecs:   utime / clockTicks,
+		cpuSystemSecs: stime / clockTicks,
+		numThreads:    numThreads,
+	}, ppid
+}
@@ -0,0 +1,409 @@
+package collector
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	common "github.com/ncabatoff/process-exporter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fixedNamer struct{}
+
+func (fixedNamer) MatchAndName(nacl common.ProcAttributes) (bool, string) {
+	return true, nacl.Name
+}
+
+// commNamer matches only processes whose comm is a key mapped to true, so
+// tests can flip a pid's matchability between scrapes.
+type commNamer map[string]bool
+
+func (n commNamer) MatchAndName(nacl common.ProcAttributes) (bool, string) {
+	if n[nacl.Name] {
+		return true, nacl.Name
+	}
+	return false, ""
+}
+
+// writeFakeProcStat is like writeFakeProc but lets the caller set the
+// ppid and num_threads fields of /proc/<pid>/stat, for exercising
+// -children and -threads.
+func writeFakeProcStat(t *testing.T, procfsPath string, pid, ppid, numThreads int, comm, cmdline string) {
+	t.Helper()
+	dir := filepath.Join(procfsPath, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stat := strconv.Itoa(pid) + " (" + comm + ") S " + strconv.Itoa(ppid) +
+		" 1 1 0 -1 0 0 0 0 0 100 50 0 0 20 0 " + strconv.Itoa(numThreads) + " 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0"
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScrape_GroupsByCgroupID(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00-jar\x00app.jar\x00", []string{"0::/kubepods/pod-aaa/containerA"})
+	writeFakeProc(t, dir, 2, "java", "java\x00-jar\x00app.jar\x00", []string{"0::/kubepods/pod-bbb/containerB"})
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath:    dir,
+		Namer:         fixedNamer{},
+		CgroupIDRegex: regexp.MustCompile(`^0::/kubepods/(pod-[a-z]+)/`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[groupKey]int{
+		{name: "java", cgroupID: "pod-aaa"}: 1,
+		{name: "java", cgroupID: "pod-bbb"}: 1,
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d: %+v", len(groups), len(want), groups)
+	}
+	for key, n := range want {
+		g, ok := groups[key]
+		if !ok {
+			t.Errorf("missing group %+v", key)
+			continue
+		}
+		if g.numProcs != n {
+			t.Errorf("group %+v: got %d procs, want %d", key, g.numProcs, n)
+		}
+	}
+}
+
+func TestScrape_WithoutCgroupIDRegex(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00-jar\x00app.jar\x00", []string{"0::/kubepods/pod-aaa/containerA"})
+	writeFakeProc(t, dir, 2, "java", "java\x00-jar\x00app.jar\x00", []string{"0::/kubepods/pod-bbb/containerB"})
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath: dir,
+		Namer:      fixedNamer{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1: %+v", len(groups), groups)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil || g.numProcs != 2 {
+		t.Fatalf("expected 2 procs merged into one group, got %+v", groups)
+	}
+}
+
+func TestScrape_JoinAttachesContainerLabels(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", []string{"0::/kubepods/besteffort/pod-aaa/docker-abc123def456abc123def456abc123def456abc123def456abc123def456.scope"})
+	writeFakeProc(t, dir, 2, "java", "java\x00", []string{"0::/kubepods/besteffort/pod-bbb/docker-0000000000000000000000000000000000000000000000000000000000000000.scope"})
+
+	join := NewStaticJoinSource([]JoinTarget{
+		{ContainerID: "abc123def456abc123def456abc123def456abc123def456abc123def456", Labels: map[string]string{"pod": "web-1", "namespace": "default"}},
+	})
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath: dir,
+		Namer:      fixedNamer{},
+		Join:       join,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2: %+v", len(groups), groups)
+	}
+
+	matched := groupKey{name: "java", containerID: "abc123def456abc123def456abc123def456abc123def456abc123def456"}
+	if g := groups[matched]; g == nil || g.numProcs != 1 {
+		t.Fatalf("missing or wrong group for matched container: %+v", groups)
+	}
+	if got, want := pc.labelValues(matched), []string{"java", "", "abc123def456abc123def456abc123def456abc123def456abc123def456", "default", "web-1"}; !equalStrings(got, want) {
+		t.Errorf("labelValues(matched) = %v, want %v", got, want)
+	}
+
+	unmatched := groupKey{name: "java", containerID: "0000000000000000000000000000000000000000000000000000000000000000"}
+	if g := groups[unmatched]; g == nil || g.numProcs != 1 {
+		t.Fatalf("missing or wrong group for unmatched container: %+v", groups)
+	}
+	if got, want := pc.labelValues(unmatched), []string{"java", "", "0000000000000000000000000000000000000000000000000000000000000000", "", ""}; !equalStrings(got, want) {
+		t.Errorf("labelValues(unmatched) = %v, want %v (empty join labels for an unmatched container)", got, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReload_SwapsNamerAndUpdatesGauges(t *testing.T) {
+	procDir := t.TempDir()
+	writeFakeProc(t, procDir, 1, "java", "java\x00", nil)
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: procDir, Namer: fixedNamer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups[groupKey{name: "java"}] == nil {
+		t.Fatal("expected fixedNamer to track the java process before reload")
+	}
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	cfgContent := "process_names:\n  - name: other\n    comm:\n    - nothingmatches\n"
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pc.Reload(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(pc.configReloadSuccess); got != 1 {
+		t.Errorf("configReloadSuccess = %v, want 1", got)
+	}
+
+	groups, err = pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("expected the reloaded config's matcher to track nothing, got %+v", groups)
+	}
+
+	if err := pc.Reload(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Fatal("expected an error reloading a nonexistent file")
+	}
+	if got := testutil.ToFloat64(pc.configReloadSuccess); got != 0 {
+		t.Errorf("configReloadSuccess = %v, want 0 after a failed reload", got)
+	}
+}
+
+func TestUsername_ResolvesFromStatus(t *testing.T) {
+	dir := t.TempDir()
+	pidDir := filepath.Join(dir, "1")
+	if err := os.MkdirAll(pidDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	self, err := user.Current()
+	if err != nil {
+		t.Skipf("can't determine current user: %v", err)
+	}
+	status := "Name:\tsleep\nUid:\t" + self.Uid + "\t" + self.Uid + "\t" + self.Uid + "\t" + self.Uid + "\n"
+	if err := os.WriteFile(filepath.Join(pidDir, "status"), []byte(status), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: dir, Namer: fixedNamer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pc.username(pidDir); got != self.Username {
+		t.Errorf("got %q, want %q", got, self.Username)
+	}
+}
+
+func TestScrape_ChildrenRollUpIntoAncestorGroup(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProcStat(t, dir, 1, 0, 1, "java", "java\x00")
+	writeFakeProcStat(t, dir, 2, 1, 1, "helper", "helper\x00")
+	writeFakeProcStat(t, dir, 3, 2, 1, "helper2", "helper2\x00")
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath: dir,
+		Namer:      commNamer{"java": true},
+		Children:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil || g.numProcs != 3 {
+		t.Errorf("expected all 3 procs rolled into the java group, got %+v", g)
+	}
+}
+
+func TestScrape_ChildrenDisabled_UntrackedDescendantIgnored(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProcStat(t, dir, 1, 0, 1, "java", "java\x00")
+	writeFakeProcStat(t, dir, 2, 1, 1, "helper", "helper\x00")
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath: dir,
+		Namer:      commNamer{"java": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil || g.numProcs != 1 {
+		t.Errorf("expected only the tracked pid without -children, got %+v", g)
+	}
+}
+
+func TestScrape_ThreadsAggregatesNumThreads(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProcStat(t, dir, 1, 0, 4, "java", "java\x00")
+	writeFakeProcStat(t, dir, 2, 0, 6, "java", "java\x00")
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath: dir,
+		Namer:      fixedNamer{},
+		Threads:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil || g.numThreads != 10 {
+		t.Errorf("expected numThreads 10, got %+v", g)
+	}
+}
+
+func TestScrape_ThreadsDisabled_LeavesNumThreadsZero(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProcStat(t, dir, 1, 0, 4, "java", "java\x00")
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: dir, Namer: fixedNamer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil || g.numThreads != 0 {
+		t.Errorf("expected numThreads to stay zero when Threads is disabled, got %+v", g)
+	}
+}
+
+func TestScrape_RecheckFalseCachesVerdictForPidLifetime(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", nil)
+
+	namer := commNamer{"java": true}
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: dir, Namer: namer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.scrape(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The namer now stops matching "java", but pid 1's cached verdict
+	// should still hold since Recheck defaults to false.
+	namer["java"] = false
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups[groupKey{name: "java"}] == nil {
+		t.Error("expected cached match to survive a namer change when Recheck is false")
+	}
+}
+
+func TestScrape_RecheckTrue_ReevaluatesEveryScrape(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", nil)
+
+	namer := commNamer{"java": true}
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: dir, Namer: namer, Recheck: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.scrape(); err != nil {
+		t.Fatal(err)
+	}
+
+	namer["java"] = false
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups[groupKey{name: "java"}] != nil {
+		t.Error("expected Recheck to drop a pid once the namer stops matching it")
+	}
+}
+
+func TestScrape_NameCachePrunedWhenPidExits(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", nil)
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: dir, Namer: fixedNamer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.scrape(); err != nil {
+		t.Fatal(err)
+	}
+	if _, cached := pc.nameCache[1]; !cached {
+		t.Fatal("expected pid 1's verdict to be cached after the first scrape")
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.scrape(); err != nil {
+		t.Fatal(err)
+	}
+	if _, cached := pc.nameCache[1]; cached {
+		t.Error("expected pid 1's cached verdict to be pruned once it exited")
+	}
+}
@@ -0,0 +1,29 @@
+package collector
+
+import "regexp"
+
+// containerIDPatterns covers the cgroup path shapes left behind by the
+// container runtimes/orchestrators we expect to see: Docker's own cgroup
+// driver, containerd via CRI, and Kubernetes' cgroupfs/systemd drivers
+// (which nest the runtime-specific scope one level deeper under
+// kubepods/...). The first pattern to match a cgroup line wins.
+var containerIDPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`docker-([0-9a-f]{12,64})\.scope`),
+	regexp.MustCompile(`cri-containerd-([0-9a-f]{12,64})\.scope`),
+	regexp.MustCompile(`kubepods[^:]*/(?:pod[0-9a-f-]+/)?([0-9a-f]{12,64})(?:\.scope)?$`),
+}
+
+// ExtractContainerID derives a container ID from a process's cgroup lines
+// (the raw contents of /proc/<pid>/cgroup), for joining against an
+// externally supplied list of container/pod metadata. It returns "" if none
+// of the recognized runtime conventions match.
+func ExtractContainerID(cgroups []string) string {
+	for _, line := range cgroups {
+		for _, re := range containerIDPatterns {
+			if m := re.FindStringSubmatch(line); len(m) > 1 {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package collector
+
+import "testing"
+
+func TestExtractContainerID(t *testing.T) {
+	cases := []struct {
+		name    string
+		cgroups []string
+		want    string
+	}{
+		{
+			name:    "docker",
+			cgroups: []string{"0::/system.slice/docker-4f6b3a1c2d3e4f5061728394a5b6c7d8e9f0a1b2c3d4e5f60718293a4b5c6d7.scope"},
+			want:    "4f6b3a1c2d3e4f5061728394a5b6c7d8e9f0a1b2c3d4e5f60718293a4b5c6d7",
+		},
+		{
+			name:    "cri-containerd",
+			cgroups: []string{"0::/kubepods.slice/cri-containerd-abcdef0123456789abcdef0123456789abcdef0123456789abcdef01234567.scope"},
+			want:    "abcdef0123456789abcdef0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			name:    "kubepods-cgroupfs",
+			cgroups: []string{"4:memory:/kubepods/burstable/pod1234/abcdef0123456789abcdef0123456789abcdef0123456789abcdef01234567"},
+			want:    "abcdef0123456789abcdef0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			name:    "no match",
+			cgroups: []string{"0::/init.scope"},
+			want:    "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := ExtractContainerID(c.cgroups); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
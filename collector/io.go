@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// iocounts holds the fields of /proc/<pid>/io, which requires the process
+// to either be owned by the reading uid or CAP_SYS_PTRACE -- hence it's
+// gated behind GatherIO rather than always read.
+type iocounts struct {
+	rchar               uint64
+	wchar               uint64
+	syscr               uint64
+	syscw               uint64
+	readBytes           uint64
+	writeBytes          uint64
+	cancelledWriteBytes uint64
+}
+
+// readIO parses /proc/<pid>/io, e.g.:
+//
+//	rchar: 1000
+//	wchar: 2000
+//	syscr: 10
+//	syscw: 20
+//	read_bytes: 4096
+//	write_bytes: 8192
+//	cancelled_write_bytes: 0
+func readIO(procfsPath string, pid int) (iocounts, error) {
+	data, err := os.ReadFile(filepath.Join(procfsPath, strconv.Itoa(pid), "io"))
+	if err != nil {
+		return iocounts{}, err
+	}
+
+	var counts iocounts
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimSuffix(fields[0], ":")
+		if name == "cancelled_write_bytes" {
+			counts.cancelledWriteBytes = parseCancelledWriteBytes(fields[1])
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "rchar":
+			counts.rchar = value
+		case "wchar":
+			counts.wchar = value
+		case "syscr":
+			counts.syscr = value
+		case "syscw":
+			counts.syscw = value
+		case "read_bytes":
+			counts.readBytes = value
+		case "write_bytes":
+			counts.writeBytes = value
+		}
+	}
+
+	return counts, nil
+}
+
+// parseCancelledWriteBytes parses the cancelled_write_bytes field, which the
+// kernel documents as signed -- it can legitimately go negative (e.g. when a
+// truncate invalidates writes already accounted for). We expose it as a
+// Prometheus counter, which can't be negative, so a negative reading falls
+// back to 0 instead of being silently dropped by a failed unsigned parse.
+func parseCancelledWriteBytes(s string) uint64 {
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return uint64(value)
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func writeFakeIO(t *testing.T, procfsPath string, pid int, rchar, wchar, syscr, syscw, readBytes, writeBytes, cancelled uint64) {
+	t.Helper()
+	dir := filepath.Join(procfsPath, strconv.Itoa(pid))
+	content := "rchar: " + strconv.FormatUint(rchar, 10) + "\n" +
+		"wchar: " + strconv.FormatUint(wchar, 10) + "\n" +
+		"syscr: " + strconv.FormatUint(syscr, 10) + "\n" +
+		"syscw: " + strconv.FormatUint(syscw, 10) + "\n" +
+		"read_bytes: " + strconv.FormatUint(readBytes, 10) + "\n" +
+		"write_bytes: " + strconv.FormatUint(writeBytes, 10) + "\n" +
+		"cancelled_write_bytes: " + strconv.FormatUint(cancelled, 10) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "io"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFakeSchedstat(t *testing.T, procfsPath string, pid int, runNs, waitNs, nrSwitch uint64) {
+	t.Helper()
+	dir := filepath.Join(procfsPath, strconv.Itoa(pid))
+	content := strconv.FormatUint(runNs, 10) + " " + strconv.FormatUint(waitNs, 10) + " " + strconv.FormatUint(nrSwitch, 10) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "schedstat"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadIO(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "sleep", "sleep\x00", nil)
+	writeFakeIO(t, dir, 1, 100, 200, 1, 2, 4096, 8192, 0)
+
+	got, err := readIO(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := iocounts{rchar: 100, wchar: 200, syscr: 1, syscw: 2, readBytes: 4096, writeBytes: 8192}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReadIO_NegativeCancelledWriteBytesFallsBackToZero(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "sleep", "sleep\x00", nil)
+	pidDir := filepath.Join(dir, "1")
+	content := "rchar: 100\nwchar: 200\nsyscr: 1\nsyscw: 2\nread_bytes: 4096\nwrite_bytes: 8192\ncancelled_write_bytes: -4096\n"
+	if err := os.WriteFile(filepath.Join(pidDir, "io"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readIO(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.cancelledWriteBytes != 0 {
+		t.Errorf("got cancelledWriteBytes = %d, want 0 for a negative kernel reading", got.cancelledWriteBytes)
+	}
+	// The other lines of the file should still parse normally.
+	if got.rchar != 100 || got.writeBytes != 8192 {
+		t.Errorf("unexpected counts from a file with a negative cancelled_write_bytes: %+v", got)
+	}
+}
+
+func TestReadSchedstat(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "sleep", "sleep\x00", nil)
+	writeFakeSchedstat(t, dir, 1, 2_000_000_000, 500_000_000, 7)
+
+	got, err := readSchedstat(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.runSecs != 2 || got.waitSecs != 0.5 || got.nrSwitch != 7 {
+		t.Errorf("unexpected schedcounts: %+v", got)
+	}
+}
+
+func TestScrape_GatherIO_AggregatesAcrossGroup(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", nil)
+	writeFakeIO(t, dir, 1, 100, 0, 1, 0, 1000, 0, 0)
+	writeFakeSchedstat(t, dir, 1, 1_000_000_000, 0, 1)
+
+	writeFakeProc(t, dir, 2, "java", "java\x00", nil)
+	writeFakeIO(t, dir, 2, 50, 0, 1, 0, 500, 0, 0)
+	writeFakeSchedstat(t, dir, 2, 1_000_000_000, 0, 1)
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath: dir,
+		Namer:      fixedNamer{},
+		GatherIO:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil {
+		t.Fatal("expected a java group")
+	}
+	if g.ioRchar != 150 || g.ioReadBytes != 1500 || g.schedSwitches != 2 || g.schedRunSecs != 2 {
+		t.Errorf("unexpected aggregation: %+v", g)
+	}
+}
+
+func TestScrape_GatherIODisabled_LeavesCountsZero(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", nil)
+	writeFakeIO(t, dir, 1, 100, 0, 1, 0, 1000, 0, 0)
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: dir, Namer: fixedNamer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil || g.ioRchar != 0 {
+		t.Errorf("expected io counts to stay zero when GatherIO is disabled, got %+v", g)
+	}
+}
@@ -0,0 +1,195 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// JoinTarget associates a container ID with a set of labels to attach to
+// every namedprocess_namegroup_* metric for processes belonging to it.
+type JoinTarget struct {
+	ContainerID string            `json:"container_id" yaml:"container_id"`
+	Labels      map[string]string `json:"labels" yaml:"labels"`
+}
+
+// JoinSource resolves container IDs (as derived by ExtractContainerID) to
+// the labels that should be attached to that container's metrics.
+type JoinSource interface {
+	// Labels returns the labels known for containerID, or nil if there's
+	// no match.
+	Labels(containerID string) map[string]string
+	// LabelNames is the fixed, sorted set of label names this source can
+	// produce; it's used to build the collector's metric descriptors, so
+	// it must not change over the lifetime of the source.
+	LabelNames() []string
+}
+
+// staticJoinSource serves a fixed list of targets, as configured directly
+// in the `join.static` section of the YAML config.
+type staticJoinSource struct {
+	byContainer map[string]map[string]string
+	labelNames  []string
+}
+
+// NewStaticJoinSource builds a JoinSource from a fixed target list.
+func NewStaticJoinSource(targets []JoinTarget) JoinSource {
+	return &staticJoinSource{
+		byContainer: indexTargets(targets),
+		labelNames:  labelNamesOf(targets),
+	}
+}
+
+func (s *staticJoinSource) Labels(containerID string) map[string]string {
+	return s.byContainer[containerID]
+}
+
+func (s *staticJoinSource) LabelNames() []string {
+	return s.labelNames
+}
+
+// refreshedJoinSource is shared by the file- and HTTP-backed sources: a
+// fetch function is called once up front (so config errors surface at
+// startup) and then on every tick of refreshInterval, with the result
+// swapped in atomically.
+type refreshedJoinSource struct {
+	fetch      func() ([]JoinTarget, error)
+	labelNames []string
+
+	current atomic.Value // map[string]map[string]string
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+func newRefreshedJoinSource(fetch func() ([]JoinTarget, error), refreshInterval time.Duration) (*refreshedJoinSource, error) {
+	targets, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &refreshedJoinSource{
+		fetch:      fetch,
+		labelNames: labelNamesOf(targets),
+		stop:       make(chan struct{}),
+	}
+	s.current.Store(indexTargets(targets))
+
+	if refreshInterval <= 0 {
+		refreshInterval = time.Minute
+	}
+	go s.refreshLoop(refreshInterval)
+
+	return s, nil
+}
+
+func (s *refreshedJoinSource) refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if targets, err := s.fetch(); err == nil {
+				s.current.Store(indexTargets(targets))
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *refreshedJoinSource) Labels(containerID string) map[string]string {
+	byContainer, _ := s.current.Load().(map[string]map[string]string)
+	return byContainer[containerID]
+}
+
+func (s *refreshedJoinSource) LabelNames() []string {
+	return s.labelNames
+}
+
+// Close stops the background refresh goroutine.
+func (s *refreshedJoinSource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+}
+
+// NewFileJoinSource builds a JoinSource that re-reads a JSON array of
+// JoinTarget from path every refreshInterval.
+func NewFileJoinSource(path string, refreshInterval time.Duration) (JoinSource, error) {
+	fetch := func() ([]JoinTarget, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("join: error reading %s: %v", path, err)
+		}
+		return decodeTargets(data)
+	}
+	return newRefreshedJoinSource(fetch, refreshInterval)
+}
+
+// NewHTTPJoinSource builds a JoinSource that polls url for a JSON array of
+// JoinTarget every refreshInterval.
+func NewHTTPJoinSource(url string, refreshInterval time.Duration) (JoinSource, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	fetch := func() ([]JoinTarget, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("join: error fetching %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("join: %s returned %s", url, resp.Status)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("join: error reading response from %s: %v", url, err)
+		}
+		return decodeTargets(data)
+	}
+	return newRefreshedJoinSource(fetch, refreshInterval)
+}
+
+func decodeTargets(data []byte) ([]JoinTarget, error) {
+	var targets []JoinTarget
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("join: error decoding target list: %v", err)
+	}
+	return targets, nil
+}
+
+func indexTargets(targets []JoinTarget) map[string]map[string]string {
+	byContainer := make(map[string]map[string]string, len(targets))
+	for _, t := range targets {
+		byContainer[t.ContainerID] = t.Labels
+	}
+	return byContainer
+}
+
+// labelNamesOf returns the sorted union of every label key across targets,
+// which becomes the fixed set of Prometheus label names the collector
+// exposes -- processes whose container has no match for a given key just
+// get an empty string there.
+func labelNamesOf(targets []JoinTarget) []string {
+	seen := map[string]bool{}
+	for _, t := range targets {
+		for k := range t.Labels {
+			seen[k] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for k := range seen {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStaticJoinSource(t *testing.T) {
+	src := NewStaticJoinSource([]JoinTarget{
+		{ContainerID: "abc123", Labels: map[string]string{"pod": "web-1", "namespace": "default"}},
+	})
+
+	if got := src.Labels("abc123"); got["pod"] != "web-1" || got["namespace"] != "default" {
+		t.Errorf("unexpected labels: %v", got)
+	}
+	if got := src.Labels("missing"); got != nil {
+		t.Errorf("expected nil for unknown container, got %v", got)
+	}
+
+	names := src.LabelNames()
+	if len(names) != 2 || names[0] != "namespace" || names[1] != "pod" {
+		t.Errorf("unexpected label names (want sorted [namespace pod]): %v", names)
+	}
+}
+
+func TestFileJoinSource_ReloadsOnTick(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+	write := func(targets []JoinTarget) {
+		data, err := json.Marshal(targets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write([]JoinTarget{{ContainerID: "a", Labels: map[string]string{"pod": "one"}}})
+
+	src, err := NewFileJoinSource(path, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.(*refreshedJoinSource).Close()
+
+	if got := src.Labels("a")["pod"]; got != "one" {
+		t.Fatalf("got %q, want %q", got, "one")
+	}
+
+	write([]JoinTarget{{ContainerID: "a", Labels: map[string]string{"pod": "two"}}})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if src.Labels("a")["pod"] == "two" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("join source never picked up updated file contents")
+}
+
+func TestHTTPJoinSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]JoinTarget{
+			{ContainerID: "xyz", Labels: map[string]string{"pod": "http-pod"}},
+		})
+	}))
+	defer srv.Close()
+
+	src, err := NewHTTPJoinSource(srv.URL, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.(*refreshedJoinSource).Close()
+
+	if got := src.Labels("xyz")["pod"]; got != "http-pod" {
+		t.Errorf("got %q, want %q", got, "http-pod")
+	}
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// schedcounts holds the three space-separated fields of /proc/<pid>/schedstat:
+// time spent on the CPU, time spent waiting on the runqueue (both in
+// nanoseconds), and the number of timeslices run. Like iocounts, this is
+// gated behind GatherIO since it's an extra read per process per scrape.
+type schedcounts struct {
+	runSecs  float64
+	waitSecs float64
+	nrSwitch uint64
+}
+
+// readSchedstat parses /proc/<pid>/schedstat, e.g. "123456 7890 42\n".
+func readSchedstat(procfsPath string, pid int) (schedcounts, error) {
+	data, err := os.ReadFile(filepath.Join(procfsPath, strconv.Itoa(pid), "schedstat"))
+	if err != nil {
+		return schedcounts{}, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return schedcounts{}, nil
+	}
+
+	runNs, _ := strconv.ParseUint(fields[0], 10, 64)
+	waitNs, _ := strconv.ParseUint(fields[1], 10, 64)
+	nrSwitch, _ := strconv.ParseUint(fields[2], 10, 64)
+
+	return schedcounts{
+		runSecs:  float64(runNs) / 1e9,
+		waitSecs: float64(waitNs) / 1e9,
+		nrSwitch: nrSwitch,
+	}, nil
+}
@@ -0,0 +1,33 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readSMapsRollupPss parses /proc/<pid>/smaps_rollup's "Pss:" line, which
+// is the kernel's own proportional share of a process's resident memory
+// (pages shared with other processes divided by the number of sharers),
+// in bytes. Reading it is gated behind GatherSMaps since smaps_rollup is
+// costlier to read than /proc/<pid>/stat, like /proc/<pid>/io.
+func readSMapsRollupPss(procfsPath string, pid int) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(procfsPath, strconv.Itoa(pid), "smaps_rollup"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.TrimSuffix(fields[0], ":") != "Pss" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+	return 0, nil
+}
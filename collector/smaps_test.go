@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func writeFakeSMapsRollup(t *testing.T, procfsPath string, pid int, pssKB uint64) {
+	t.Helper()
+	dir := filepath.Join(procfsPath, strconv.Itoa(pid))
+	content := "Rss:            4096 kB\nPss:            " + strconv.FormatUint(pssKB, 10) + " kB\n"
+	if err := os.WriteFile(filepath.Join(dir, "smaps_rollup"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSMapsRollupPss(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "sleep", "sleep\x00", nil)
+	writeFakeSMapsRollup(t, dir, 1, 2048)
+
+	got, err := readSMapsRollupPss(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(2048 * 1024); got != want {
+		t.Errorf("got %d bytes, want %d", got, want)
+	}
+}
+
+func TestScrape_GatherSMaps_AggregatesAcrossGroup(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", nil)
+	writeFakeSMapsRollup(t, dir, 1, 1000)
+	writeFakeProc(t, dir, 2, "java", "java\x00", nil)
+	writeFakeSMapsRollup(t, dir, 2, 500)
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{
+		ProcFSPath:  dir,
+		Namer:       fixedNamer{},
+		GatherSMaps: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if want := uint64(1500 * 1024); g == nil || g.memPropResidentBytes != want {
+		t.Errorf("unexpected aggregation: %+v", g)
+	}
+}
+
+func TestScrape_GatherSMapsDisabled_LeavesMemZero(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeProc(t, dir, 1, "java", "java\x00", nil)
+	writeFakeSMapsRollup(t, dir, 1, 1000)
+
+	pc, err := NewProcessCollector(ProcessCollectorOption{ProcFSPath: dir, Namer: fixedNamer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := pc.scrape()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := groups[groupKey{name: "java"}]
+	if g == nil || g.memPropResidentBytes != 0 {
+		t.Errorf("expected mem to stay zero when GatherSMaps is disabled, got %+v", g)
+	}
+}
@@ -0,0 +1,214 @@
+// Package config implements the YAML-based alternative to the
+// -procnames/-namemapping command-line flags: a list of matchers, each
+// with rules for matching a process's name, exe path or command line, and
+// a name under which matching processes should be grouped together.
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"text/template"
+
+	common "github.com/ncabatoff/process-exporter"
+	"gopkg.in/yaml.v2"
+)
+
+// MatchNamer is satisfied by anything that can be used as a matcher entry
+// in a Config's MatchNamers list.
+type MatchNamer interface {
+	common.MatchNamer
+	// String returns the name this matcher was configured with, for
+	// logging/debugging purposes.
+	String() string
+}
+
+// MatcherGroup is a single entry under the `process_names:` section of a
+// config file: a process matches it if any of Comm, Exe or Cmdline rules
+// matches, and upon matching is grouped under Name. If Name contains
+// "{{", it's parsed as a Go text/template (see template.go) with access to
+// the process's attributes and any named capture groups from Cmdline;
+// otherwise it's treated as a literal pattern with $1, $2, ... replaced by
+// the capture groups of whichever rule matched.
+type MatcherGroup struct {
+	Name    string   `yaml:"name"`
+	Comm    []string `yaml:"comm,omitempty"`
+	Exe     []string `yaml:"exe,omitempty"`
+	Cmdline []string `yaml:"cmdline,omitempty"`
+
+	commRegexps    []*regexp.Regexp
+	exeRegexps     []*regexp.Regexp
+	cmdlineRegexps []*regexp.Regexp
+	nameTemplate   *template.Template
+}
+
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, p := range patterns {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling regexp %q: %v", p, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (mg *MatcherGroup) compile() error {
+	var err error
+	if mg.commRegexps, err = compileAll(mg.Comm); err != nil {
+		return err
+	}
+	if mg.exeRegexps, err = compileAll(mg.Exe); err != nil {
+		return err
+	}
+	if mg.cmdlineRegexps, err = compileAll(mg.Cmdline); err != nil {
+		return err
+	}
+	if isTemplate(mg.Name) {
+		if mg.nameTemplate, err = compileTemplate(mg.Name, mg.cmdlineRegexps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mg *MatcherGroup) String() string {
+	return mg.Name
+}
+
+func firstMatch(regexps []*regexp.Regexp, s string) []string {
+	for _, r := range regexps {
+		if m := r.FindStringSubmatch(s); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// MatchAndName implements common.MatchNamer.
+func (mg *MatcherGroup) MatchAndName(nacl common.ProcAttributes) (bool, string) {
+	cmdline := ""
+	if len(nacl.Cmdline) > 0 {
+		cmdline = nacl.Cmdline[0]
+		for _, tok := range nacl.Cmdline[1:] {
+			cmdline += " " + tok
+		}
+	}
+
+	matches := firstMatch(mg.commRegexps, nacl.Name)
+	if matches == nil {
+		matches = firstMatch(mg.exeRegexps, nacl.Exe)
+	}
+	if matches == nil {
+		matches = firstMatch(mg.cmdlineRegexps, cmdline)
+	}
+	if matches == nil {
+		if len(mg.commRegexps) == 0 && len(mg.exeRegexps) == 0 && len(mg.cmdlineRegexps) == 0 {
+			matches = []string{nacl.Name}
+		} else {
+			return false, ""
+		}
+	}
+
+	if mg.nameTemplate != nil {
+		name, err := renderTemplate(mg.nameTemplate, nacl, mg.cmdlineRegexps, cmdline)
+		if err != nil {
+			return false, ""
+		}
+		return true, name
+	}
+
+	name := mg.Name
+	for i, group := range matches {
+		token := fmt.Sprintf("$%d", i)
+		name = regexp.MustCompile(regexp.QuoteMeta(token)).ReplaceAllString(name, group)
+	}
+	return true, name
+}
+
+// allMatchers applies each MatcherGroup in order, returning the result of
+// the first one that matches.
+type allMatchers []*MatcherGroup
+
+func (ms allMatchers) MatchAndName(nacl common.ProcAttributes) (bool, string) {
+	for _, m := range ms {
+		if ok, name := m.MatchAndName(nacl); ok {
+			return true, name
+		}
+	}
+	return false, ""
+}
+
+// Config is the parsed form of a process-exporter YAML config file.
+type Config struct {
+	ProcessNames []*MatcherGroup `yaml:"process_names"`
+
+	// CgroupIDRegexStr, if set, is applied to each line of
+	// /proc/<pid>/cgroup to derive a cgroup_id label/grouping key; see
+	// the -cgroup.id-regex flag for the command-line equivalent.
+	CgroupIDRegexStr string `yaml:"cgroup_id_regex,omitempty"`
+
+	// MatchNamers is the combined matcher built from ProcessNames, used
+	// by the collector to decide what to track and how to name it.
+	MatchNamers common.MatchNamer `yaml:"-"`
+
+	// CgroupIDRegex is CgroupIDRegexStr, compiled.
+	CgroupIDRegex *regexp.Regexp `yaml:"-"`
+
+	// Join configures enrichment of metrics with labels from an
+	// externally-supplied, container-ID-keyed target list. Nil if the
+	// config file has no `join:` section.
+	Join *JoinConfig `yaml:"join,omitempty"`
+
+	// GatherIO is the config-file equivalent of the -gather-io flag.
+	GatherIO bool `yaml:"gather_io,omitempty"`
+}
+
+// ReadFile parses and validates the config file at path.
+func ReadFile(path string, debug bool) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %q: %v", path, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %q: %v", path, err)
+	}
+
+	var matchers allMatchers
+	for i, mg := range cfg.ProcessNames {
+		if mg.Name == "" {
+			return nil, fmt.Errorf("process_names[%d]: name is required", i)
+		}
+		if err := mg.compile(); err != nil {
+			return nil, fmt.Errorf("process_names[%d]: %v", i, err)
+		}
+		matchers = append(matchers, mg)
+	}
+	cfg.MatchNamers = matchers
+
+	if cfg.CgroupIDRegexStr != "" {
+		re, err := regexp.Compile(cfg.CgroupIDRegexStr)
+		if err != nil {
+			return nil, fmt.Errorf("cgroup_id_regex: %v", err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("cgroup_id_regex: must have a capture group")
+		}
+		cfg.CgroupIDRegex = re
+	}
+
+	if cfg.Join != nil {
+		if err := cfg.Join.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if debug {
+		fmt.Printf("config: loaded %d matcher(s) from %s\n", len(cfg.ProcessNames), path)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	common "github.com/ncabatoff/process-exporter"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFile_CgroupIDRegex(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: "{{.Comm}}"
+    comm:
+    - .+
+cgroup_id_regex: "^0::(/.*)$"
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CgroupIDRegex == nil {
+		t.Fatal("expected CgroupIDRegex to be compiled")
+	}
+	if m := cfg.CgroupIDRegex.FindStringSubmatch("0::/a.scope"); len(m) < 2 || m[1] != "/a.scope" {
+		t.Errorf("unexpected match result: %v", m)
+	}
+}
+
+func TestReadFile_CgroupIDRegexRequiresCaptureGroup(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: foo
+    comm:
+    - foo
+cgroup_id_regex: "^0::.*$"
+`)
+
+	if _, err := ReadFile(path, false); err == nil {
+		t.Fatal("expected error for cgroup_id_regex without capture group")
+	}
+}
+
+func TestMatcherGroup_NameSubstitution(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: "java-$1"
+    cmdline:
+    - "-jar\\s+(\\S+)\\.jar"
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, name := cfg.MatchNamers.MatchAndName(common.ProcAttributes{
+		Name:    "java",
+		Cmdline: []string{"java", "-jar", "app.jar"},
+	})
+	if !ok || name != "java-app" {
+		t.Errorf("got (%v, %q), want (true, \"java-app\")", ok, name)
+	}
+}
+
+func TestMatcherGroup_ExeMatchesExecutablePathNotComm(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: myapp
+    exe:
+    - "^/usr/local/bin/myapp$"
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, name := cfg.MatchNamers.MatchAndName(common.ProcAttributes{
+		Name: "myapp",
+		Exe:  "/usr/local/bin/myapp",
+	})
+	if !ok || name != "myapp" {
+		t.Errorf("got (%v, %q), want (true, \"myapp\")", ok, name)
+	}
+
+	if ok, _ := cfg.MatchNamers.MatchAndName(common.ProcAttributes{
+		Name: "myapp",
+		Exe:  "/opt/other/myapp",
+	}); ok {
+		t.Error("expected no match for a process with the same comm but a different exe path")
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// JoinTarget associates a container ID with the labels that should be
+// attached to that container's metrics.
+type JoinTarget struct {
+	ContainerID string            `yaml:"container_id"`
+	Labels      map[string]string `yaml:"labels"`
+}
+
+// JoinConfig is the `join:` section of a config file: exactly one of
+// Static, File or URL selects where the target list comes from.
+type JoinConfig struct {
+	Static          []JoinTarget  `yaml:"static,omitempty"`
+	File            string        `yaml:"file,omitempty"`
+	URL             string        `yaml:"url,omitempty"`
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+}
+
+// validate checks that exactly one source is configured.
+func (jc *JoinConfig) validate() error {
+	set := 0
+	if jc.Static != nil {
+		set++
+	}
+	if jc.File != "" {
+		set++
+	}
+	if jc.URL != "" {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("join: exactly one of static, file or url must be set")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import "testing"
+
+func TestReadFile_JoinStatic(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: foo
+    comm:
+    - foo
+join:
+  static:
+    - container_id: abc123
+      labels:
+        pod: web-1
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Join == nil || len(cfg.Join.Static) != 1 || cfg.Join.Static[0].ContainerID != "abc123" {
+		t.Fatalf("unexpected join config: %+v", cfg.Join)
+	}
+}
+
+func TestReadFile_JoinRequiresExactlyOneSource(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: foo
+    comm:
+    - foo
+join:
+  file: /tmp/targets.json
+  url: http://example/targets
+`)
+
+	if _, err := ReadFile(path, false); err == nil {
+		t.Fatal("expected error when both file and url are set")
+	}
+}
+
+func TestReadFile_JoinRequiresOneSource(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: foo
+    comm:
+    - foo
+join: {}
+`)
+
+	if _, err := ReadFile(path, false); err == nil {
+		t.Fatal("expected error when join has no source configured")
+	}
+}
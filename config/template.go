@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	common "github.com/ncabatoff/process-exporter"
+)
+
+// templateContext is the data made available to a matcher's name template.
+type templateContext struct {
+	// Comm is the process's /proc/<pid>/stat name, e.g. "java".
+	Comm string
+	// ExeBase is the basename of the resolved /proc/<pid>/exe symlink, or
+	// of the first cmdline token if that symlink couldn't be read.
+	ExeBase string
+	// Username is the resolved owner of the process.
+	Username string
+	// Cmdline is the full argv, so e.g. {{index .Cmdline 0}} works.
+	Cmdline []string
+	// Matches holds the named capture groups ("(?P<name>...)") found by
+	// whichever of this matcher's cmdline patterns matched, e.g.
+	// {{.Matches.jar}}.
+	Matches map[string]string
+}
+
+// matchesRefRegexp finds references to named capture groups in a name
+// template, so they can be validated against the matcher's cmdline
+// patterns at load time.
+var matchesRefRegexp = regexp.MustCompile(`\.Matches\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// isTemplate reports whether a matcher's name field should be interpreted
+// as a Go text/template rather than a literal "$1"-substitution pattern.
+func isTemplate(name string) bool {
+	return strings.Contains(name, "{{")
+}
+
+// compileTemplate parses name as a text/template and checks that every
+// {{.Matches.NAME}} it references corresponds to a named capture group in
+// one of the matcher's cmdline patterns.
+func compileTemplate(name string, cmdlineRegexps []*regexp.Regexp) (*template.Template, error) {
+	tmpl, err := template.New("name").Option("missingkey=zero").Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing name template %q: %v", name, err)
+	}
+
+	known := map[string]bool{}
+	for _, re := range cmdlineRegexps {
+		for _, n := range re.SubexpNames() {
+			if n != "" {
+				known[n] = true
+			}
+		}
+	}
+
+	for _, m := range matchesRefRegexp.FindAllStringSubmatch(name, -1) {
+		if !known[m[1]] {
+			return nil, fmt.Errorf("name template %q references .Matches.%s, but no cmdline pattern defines that capture group", name, m[1])
+		}
+	}
+
+	return tmpl, nil
+}
+
+// namedCmdlineMatches runs the matcher's cmdline patterns against the full
+// command line and returns the named capture groups of whichever pattern
+// matches first.
+func namedCmdlineMatches(cmdlineRegexps []*regexp.Regexp, cmdline string) map[string]string {
+	matches := map[string]string{}
+	for _, re := range cmdlineRegexps {
+		m := re.FindStringSubmatch(cmdline)
+		if m == nil {
+			continue
+		}
+		for i, n := range re.SubexpNames() {
+			if n != "" {
+				matches[n] = m[i]
+			}
+		}
+		break
+	}
+	return matches
+}
+
+// renderTemplate executes tmpl against the attributes of a matched
+// process. A template execution error (e.g. `index .Cmdline 5` on a
+// shorter argv) is treated as a non-match rather than a panic.
+func renderTemplate(tmpl *template.Template, nacl common.ProcAttributes, cmdlineRegexps []*regexp.Regexp, cmdline string) (string, error) {
+	ctx := templateContext{
+		Comm:     nacl.Name,
+		Username: nacl.Username,
+		Cmdline:  nacl.Cmdline,
+		Matches:  namedCmdlineMatches(cmdlineRegexps, cmdline),
+	}
+	if nacl.Exe != "" {
+		ctx.ExeBase = filepath.Base(nacl.Exe)
+	} else if len(nacl.Cmdline) > 0 {
+		ctx.ExeBase = filepath.Base(nacl.Cmdline[0])
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ctx); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
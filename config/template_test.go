@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+
+	common "github.com/ncabatoff/process-exporter"
+)
+
+func TestTemplateNamer_ExeBaseAndMatches(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: "{{.ExeBase}}-{{.Matches.jar}}"
+    cmdline:
+    - "-jar\\s+(?P<jar>\\S+)\\.jar"
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, name := cfg.MatchNamers.MatchAndName(common.ProcAttributes{
+		Name:    "java",
+		Cmdline: []string{"/usr/bin/java", "-jar", "app.jar"},
+	})
+	if !ok || name != "java-app" {
+		t.Errorf("got (%v, %q), want (true, \"java-app\")", ok, name)
+	}
+}
+
+func TestTemplateNamer_IndexCmdline(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: "{{index .Cmdline 0}}"
+    comm:
+    - .+
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, name := cfg.MatchNamers.MatchAndName(common.ProcAttributes{
+		Name:    "bash",
+		Cmdline: []string{"/bin/bash", "-c", "sleep 1"},
+	})
+	if !ok || name != "/bin/bash" {
+		t.Errorf("got (%v, %q), want (true, \"/bin/bash\")", ok, name)
+	}
+}
+
+func TestTemplateNamer_ExeBasePrefersResolvedExeOverCmdline(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: "{{.ExeBase}}"
+    comm:
+    - .+
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, name := cfg.MatchNamers.MatchAndName(common.ProcAttributes{
+		Name:    "myapp",
+		Exe:     "/usr/local/bin/myapp",
+		Cmdline: []string{"-bash"},
+	})
+	if !ok || name != "myapp" {
+		t.Errorf("got (%v, %q), want (true, \"myapp\") derived from Exe, not argv0", ok, name)
+	}
+}
+
+func TestTemplateNamer_ExeBaseFallsBackToCmdlineWhenExeEmpty(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: "{{.ExeBase}}"
+    comm:
+    - .+
+`)
+
+	cfg, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, name := cfg.MatchNamers.MatchAndName(common.ProcAttributes{
+		Name:    "bash",
+		Cmdline: []string{"/bin/bash"},
+	})
+	if !ok || name != "bash" {
+		t.Errorf("got (%v, %q), want (true, \"bash\") derived from Cmdline[0] when Exe couldn't be read", ok, name)
+	}
+}
+
+func TestReadFile_RejectsUnknownMatchesReference(t *testing.T) {
+	path := writeConfig(t, `
+process_names:
+  - name: "{{.Matches.nonexistent}}"
+    comm:
+    - .+
+`)
+
+	if _, err := ReadFile(path, false); err == nil {
+		t.Fatal("expected error for unknown .Matches reference")
+	}
+}
@@ -0,0 +1,37 @@
+// Package process_exporter provides types shared by the config and
+// collector packages: the attributes gathered about a process, and the
+// interface used to decide whether a process should be tracked and what
+// group it belongs to.
+package process_exporter
+
+// ProcAttributes describes a process as read out of /proc, independent of
+// whatever file format or API it came from.  It's what gets passed to a
+// MatchNamer so it can decide whether to track the process, and if so
+// what name to group it under.
+type ProcAttributes struct {
+	// Name is the value found in the second field of /proc/<pid>/stat,
+	// truncated at 15 chars by the kernel.
+	Name string
+	// Exe is the resolved target of the /proc/<pid>/exe symlink, i.e.
+	// the full path to the process's executable. Empty if it couldn't
+	// be read (the process exited, or we lack permission).
+	Exe string
+	// Cmdline is the parsed contents of /proc/<pid>/cmdline.
+	Cmdline []string
+	// Username is the resolved owner of the process, or the numeric uid
+	// as a string if the name can't be resolved.
+	Username string
+	// Cgroups holds the raw lines of /proc/<pid>/cgroup, one per
+	// hierarchy/controller.  Empty if the kernel or procfs doesn't
+	// expose cgroup membership.
+	Cgroups []string
+}
+
+// MatchNamer is the interface that decides whether a given process should
+// be tracked, and if so what name should be used to group it with related
+// processes.
+type MatchNamer interface {
+	// MatchAndName returns whether the process identified by nacl should
+	// be tracked, and if so, under what group name.
+	MatchAndName(nacl ProcAttributes) (bool, string)
+}
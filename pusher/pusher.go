@@ -0,0 +1,110 @@
+// Package pusher periodically gathers metrics from a prometheus.Gatherer
+// and ships them to an OTLP collector, so process-exporter can be run in
+// scrape mode, push mode, or both at once during a migration.
+package pusher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// Protocol selects the wire format used to talk to the OTLP endpoint.
+type Protocol string
+
+const (
+	ProtocolGRPC         Protocol = "grpc"
+	ProtocolHTTPProtobuf Protocol = "http/protobuf"
+)
+
+// Config holds the settings for a Pusher, mirroring the --otlp.* flags.
+type Config struct {
+	Endpoint string
+	Protocol Protocol
+	Interval time.Duration
+	Headers  map[string]string
+}
+
+// Pusher periodically gathers metrics and exports them over OTLP.
+type Pusher struct {
+	cfg      Config
+	exporter metric.Exporter
+	starts   *seriesStartTimes
+}
+
+// New builds a Pusher from cfg; it dials (or prepares to dial) the
+// endpoint but doesn't start pushing until Run is called.
+func New(cfg Config) (*Pusher, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("pusher: endpoint is required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("pusher: error building %s exporter for %s: %v", cfg.Protocol, cfg.Endpoint, err)
+	}
+
+	return &Pusher{cfg: cfg, exporter: exporter, starts: newSeriesStartTimes()}, nil
+}
+
+func newExporter(cfg Config) (metric.Exporter, error) {
+	ctx := context.Background()
+	switch cfg.Protocol {
+	case ProtocolHTTPProtobuf:
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpoint(cfg.Endpoint),
+			otlpmetrichttp.WithHeaders(cfg.Headers),
+		)
+	case ProtocolGRPC, "":
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpoint(cfg.Endpoint),
+			otlpmetricgrpc.WithHeaders(cfg.Headers),
+		)
+	default:
+		return nil, fmt.Errorf("unknown protocol %q, want %q or %q", cfg.Protocol, ProtocolGRPC, ProtocolHTTPProtobuf)
+	}
+}
+
+// Run gathers metrics from gatherer and exports them every cfg.Interval,
+// until ctx is cancelled. Export errors are logged and don't stop the loop.
+func (p *Pusher) Run(ctx context.Context, gatherer prometheus.Gatherer, hostname string) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.pushOnce(ctx, gatherer, hostname)
+		}
+	}
+}
+
+func (p *Pusher) pushOnce(ctx context.Context, gatherer prometheus.Gatherer, hostname string) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pusher: error gathering metrics: %v\n", err)
+		return
+	}
+
+	for _, rm := range toResourceMetrics(families, hostname, p.starts) {
+		if err := p.exporter.Export(ctx, rm); err != nil {
+			fmt.Fprintf(os.Stderr, "pusher: error exporting to %s: %v\n", p.cfg.Endpoint, err)
+		}
+	}
+}
+
+// Shutdown releases the exporter's underlying connection.
+func (p *Pusher) Shutdown(ctx context.Context) error {
+	return p.exporter.Shutdown(ctx)
+}
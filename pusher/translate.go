@@ -0,0 +1,221 @@
+package pusher
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// scopeName identifies process-exporter as the producer of these metrics,
+// per the OTLP instrumentation-scope convention.
+const scopeName = "github.com/ncabatoff/process-exporter"
+
+// seriesStartTimes remembers, per counter series, the time its cumulative
+// window began. metricdata.DataPoint.StartTime documents that field as
+// "when the timeseries was started", so it must stay fixed across exports
+// of the same series -- recomputing it on every push makes every export
+// look like a freshly-reset counter to any OTLP consumer that checks
+// StartTimeUnixNano for resets (e.g. an OTLP-to-remote-write bridge),
+// which breaks rate()/increase() on every counter this pusher ships.
+type seriesStartTimes struct {
+	times map[string]time.Time
+}
+
+func newSeriesStartTimes() *seriesStartTimes {
+	return &seriesStartTimes{times: make(map[string]time.Time)}
+}
+
+// get returns the start time for key, recording now as its start the
+// first time key is seen.
+func (s *seriesStartTimes) get(key string, now time.Time) time.Time {
+	if t, ok := s.times[key]; ok {
+		return t
+	}
+	s.times[key] = now
+	return now
+}
+
+// prune drops every tracked series whose key isn't in seen. Without this,
+// every distinct label set ever reported -- including container_id/pod
+// values from the Join feature, which churn constantly under Kubernetes --
+// would stay in the map for the life of the process.
+func (s *seriesStartTimes) prune(seen map[string]bool) {
+	for key := range s.times {
+		if !seen[key] {
+			delete(s.times, key)
+		}
+	}
+}
+
+// seriesKey identifies a single series within a metric family by its
+// label set, so two metrics in the same family (e.g. different groupname
+// values) get independent start times.
+func seriesKey(famName string, labels []*dto.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	sort.Strings(pairs)
+	return famName + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// resourceLabelAttrs maps the labels that identify a process's container --
+// the container_id label the Join feature (see collector.labelValues)
+// always attaches, plus a "pod" label when the join targets supply one --
+// to their OTel semantic-convention resource attribute names. A scrape is
+// split into one ResourceMetrics per distinct combination of these found,
+// so container/pod identity becomes an OTLP resource attribute rather
+// than just a point attribute. Any other join label -- a user-defined
+// "namespace" or "team", say -- has no fixed name here, so it stays a
+// point attribute.
+var resourceLabelAttrs = map[string]attribute.Key{
+	"container_id": attribute.Key("container.id"),
+	"pod":          attribute.Key("k8s.pod.name"),
+}
+
+// splitResourceLabels separates a series' labels into the subset promoted
+// to OTLP resource attributes and the rest, which stay point attributes.
+func splitResourceLabels(labels []*dto.LabelPair) (resAttrs []attribute.KeyValue, pointLabels []*dto.LabelPair) {
+	for _, l := range labels {
+		if key, ok := resourceLabelAttrs[l.GetName()]; ok && l.GetValue() != "" {
+			resAttrs = append(resAttrs, key.String(l.GetValue()))
+			continue
+		}
+		pointLabels = append(pointLabels, l)
+	}
+	sort.Slice(resAttrs, func(i, j int) bool { return resAttrs[i].Key < resAttrs[j].Key })
+	return resAttrs, pointLabels
+}
+
+// resourceAttrsKey is a stable string key for a sorted attribute list, used
+// to group series into resourceBuckets.
+func resourceAttrsKey(attrs []attribute.KeyValue) string {
+	parts := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		parts = append(parts, string(a.Key)+"="+a.Value.Emit())
+	}
+	return strings.Join(parts, ",")
+}
+
+// resourceBucket accumulates the OTLP metrics sharing one distinct set of
+// resource attributes within a scrape.
+type resourceBucket struct {
+	attrs    []attribute.KeyValue
+	byName   map[string]*metricdata.Metrics
+	famOrder []string
+}
+
+func (b *resourceBucket) metricsFor(famName, help string) *metricdata.Metrics {
+	if m, ok := b.byName[famName]; ok {
+		return m
+	}
+	m := &metricdata.Metrics{Name: famName, Description: help}
+	b.byName[famName] = m
+	b.famOrder = append(b.famOrder, famName)
+	return m
+}
+
+// toResourceMetrics converts a Prometheus scrape into the OTLP metric
+// model: counters become monotonic, cumulative Sums and gauges become
+// Gauges. Every Prometheus label is preserved as a point attribute except
+// the ones promoted to resource attributes by splitResourceLabels.
+// Histograms and summaries (no namedprocess_* metric is one, but the Go
+// runtime's go_gc_duration_seconds summary is) are skipped rather than
+// guessed at. starts tracks each counter series'
+// StartTime across calls; pass the same instance on every call for a
+// given Pusher.
+//
+// One *metricdata.ResourceMetrics is returned per distinct container/pod
+// combination seen in the scrape, always including one for series with
+// neither (e.g. when the Join feature isn't in use) -- that one is always
+// first, so callers that don't use Join can keep assuming a single result.
+func toResourceMetrics(families []*dto.MetricFamily, hostname string, starts *seriesStartTimes) []*metricdata.ResourceMetrics {
+	now := time.Now()
+	seen := make(map[string]bool)
+	buckets := make(map[string]*resourceBucket)
+	var order []string
+
+	bucketFor := func(resAttrs []attribute.KeyValue) *resourceBucket {
+		key := resourceAttrsKey(resAttrs)
+		b, ok := buckets[key]
+		if !ok {
+			b = &resourceBucket{attrs: resAttrs, byName: make(map[string]*metricdata.Metrics)}
+			buckets[key] = b
+			order = append(order, key)
+		}
+		return b
+	}
+	bucketFor(nil) // always emit a base ResourceMetrics, even with nothing in it
+
+	for _, fam := range families {
+		switch fam.GetType() {
+		case dto.MetricType_COUNTER:
+			for _, m := range fam.GetMetric() {
+				resAttrs, pointLabels := splitResourceLabels(m.GetLabel())
+				key := seriesKey(fam.GetName(), m.GetLabel())
+				seen[key] = true
+				start := starts.get(key, now)
+
+				mm := bucketFor(resAttrs).metricsFor(fam.GetName(), fam.GetHelp())
+				sum, _ := mm.Data.(metricdata.Sum[float64])
+				sum.Temporality = metricdata.CumulativeTemporality
+				sum.IsMonotonic = true
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: labelsToAttributes(pointLabels),
+					StartTime:  start,
+					Time:       now,
+					Value:      m.GetCounter().GetValue(),
+				})
+				mm.Data = sum
+			}
+		case dto.MetricType_GAUGE:
+			for _, m := range fam.GetMetric() {
+				resAttrs, pointLabels := splitResourceLabels(m.GetLabel())
+				mm := bucketFor(resAttrs).metricsFor(fam.GetName(), fam.GetHelp())
+				gauge, _ := mm.Data.(metricdata.Gauge[float64])
+				gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: labelsToAttributes(pointLabels),
+					Time:       now,
+					Value:      m.GetGauge().GetValue(),
+				})
+				mm.Data = gauge
+			}
+		}
+	}
+	starts.prune(seen)
+
+	rms := make([]*metricdata.ResourceMetrics, 0, len(order))
+	for _, key := range order {
+		b := buckets[key]
+		attrs := append([]attribute.KeyValue{
+			attribute.String("service.name", "process-exporter"),
+			attribute.String("host.name", hostname),
+		}, b.attrs...)
+		metrics := make([]metricdata.Metrics, 0, len(b.famOrder))
+		for _, name := range b.famOrder {
+			metrics = append(metrics, *b.byName[name])
+		}
+		rms = append(rms, &metricdata.ResourceMetrics{
+			Resource: resource.NewSchemaless(attrs...),
+			ScopeMetrics: []metricdata.ScopeMetrics{{
+				Scope:   instrumentation.Scope{Name: scopeName},
+				Metrics: metrics,
+			}},
+		})
+	}
+	return rms
+}
+
+func labelsToAttributes(labels []*dto.LabelPair) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		kvs = append(kvs, attribute.String(l.GetName(), l.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}
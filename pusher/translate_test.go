@@ -0,0 +1,198 @@
+package pusher
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func strPtr(s string) *string                  { return &s }
+func typePtr(t dto.MetricType) *dto.MetricType { return &t }
+func float64Ptr(f float64) *float64            { return &f }
+
+func TestToResourceMetrics_CounterBecomesMonotonicCumulativeSum(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: strPtr("namedprocess_namegroup_cpu_seconds_total"),
+			Help: strPtr("Cpu usage in seconds"),
+			Type: typePtr(dto.MetricType_COUNTER),
+			Metric: []*dto.Metric{
+				{
+					Label:   []*dto.LabelPair{{Name: strPtr("groupname"), Value: strPtr("java")}},
+					Counter: &dto.Counter{Value: float64Ptr(12.5)},
+				},
+			},
+		},
+	}
+
+	rms := toResourceMetrics(families, "host1", newSeriesStartTimes())
+	if len(rms) != 1 || len(rms[0].ScopeMetrics) != 1 || len(rms[0].ScopeMetrics[0].Metrics) != 1 {
+		t.Fatalf("unexpected shape: %+v", rms)
+	}
+
+	m := rms[0].ScopeMetrics[0].Metrics[0]
+	sum, ok := m.Data.(metricdata.Sum[float64])
+	if !ok {
+		t.Fatalf("expected Sum, got %T", m.Data)
+	}
+	if !sum.IsMonotonic || sum.Temporality != metricdata.CumulativeTemporality {
+		t.Errorf("expected monotonic cumulative sum, got %+v", sum)
+	}
+	if len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 12.5 {
+		t.Errorf("unexpected data points: %+v", sum.DataPoints)
+	}
+}
+
+func TestToResourceMetrics_GaugeBecomesGauge(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: strPtr("namedprocess_namegroup_num_procs"),
+			Type: typePtr(dto.MetricType_GAUGE),
+			Metric: []*dto.Metric{
+				{Gauge: &dto.Gauge{Value: float64Ptr(3)}},
+			},
+		},
+	}
+
+	rms := toResourceMetrics(families, "host1", newSeriesStartTimes())
+	m := rms[0].ScopeMetrics[0].Metrics[0]
+	gauge, ok := m.Data.(metricdata.Gauge[float64])
+	if !ok {
+		t.Fatalf("expected Gauge, got %T", m.Data)
+	}
+	if len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 3 {
+		t.Errorf("unexpected data points: %+v", gauge.DataPoints)
+	}
+}
+
+func TestToResourceMetrics_SkipsHistograms(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{Name: strPtr("some_histogram"), Type: typePtr(dto.MetricType_HISTOGRAM)},
+	}
+
+	rms := toResourceMetrics(families, "host1", newSeriesStartTimes())
+	if len(rms) != 1 || len(rms[0].ScopeMetrics[0].Metrics) != 0 {
+		t.Errorf("expected histograms to be skipped, got %+v", rms)
+	}
+}
+
+func TestToResourceMetrics_CounterStartTimeFixedAcrossExports(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: strPtr("namedprocess_namegroup_cpu_seconds_total"),
+			Type: typePtr(dto.MetricType_COUNTER),
+			Metric: []*dto.Metric{
+				{
+					Label:   []*dto.LabelPair{{Name: strPtr("groupname"), Value: strPtr("java")}},
+					Counter: &dto.Counter{Value: float64Ptr(1)},
+				},
+			},
+		},
+	}
+
+	starts := newSeriesStartTimes()
+	first := toResourceMetrics(families, "host1", starts)
+	firstStart := first[0].ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[float64]).DataPoints[0].StartTime
+
+	families[0].Metric[0].Counter = &dto.Counter{Value: float64Ptr(2)}
+	second := toResourceMetrics(families, "host1", starts)
+	sp := second[0].ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[float64]).DataPoints[0]
+
+	if !sp.StartTime.Equal(firstStart) {
+		t.Errorf("StartTime changed across exports of the same series: got %v, want %v", sp.StartTime, firstStart)
+	}
+	if !sp.Time.After(firstStart) && !sp.Time.Equal(firstStart) {
+		t.Errorf("Time should advance (or at minimum not precede) StartTime, got %v", sp.Time)
+	}
+}
+
+func TestToResourceMetrics_PrunesStartTimesForSeriesNoLongerReported(t *testing.T) {
+	makeFamilies := func(groups ...string) []*dto.MetricFamily {
+		var metrics []*dto.Metric
+		for _, g := range groups {
+			metrics = append(metrics, &dto.Metric{
+				Label:   []*dto.LabelPair{{Name: strPtr("groupname"), Value: strPtr(g)}},
+				Counter: &dto.Counter{Value: float64Ptr(1)},
+			})
+		}
+		return []*dto.MetricFamily{
+			{
+				Name:   strPtr("namedprocess_namegroup_cpu_seconds_total"),
+				Type:   typePtr(dto.MetricType_COUNTER),
+				Metric: metrics,
+			},
+		}
+	}
+
+	starts := newSeriesStartTimes()
+	toResourceMetrics(makeFamilies("java", "pod-that-churns"), "host1", starts)
+	if len(starts.times) != 2 {
+		t.Fatalf("expected 2 tracked series after first scrape, got %d", len(starts.times))
+	}
+
+	// "pod-that-churns" stops being reported, as happens continuously
+	// under Kubernetes when the Join feature is in use.
+	toResourceMetrics(makeFamilies("java"), "host1", starts)
+	if len(starts.times) != 1 {
+		t.Errorf("expected the departed series to be pruned, got %d tracked: %+v", len(starts.times), starts.times)
+	}
+}
+
+func TestToResourceMetrics_ContainerAndPodBecomeResourceAttributes(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: strPtr("namedprocess_namegroup_num_procs"),
+			Type: typePtr(dto.MetricType_GAUGE),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{
+						{Name: strPtr("groupname"), Value: strPtr("java")},
+						{Name: strPtr("container_id"), Value: strPtr("abc123")},
+						{Name: strPtr("pod"), Value: strPtr("web-1")},
+						{Name: strPtr("namespace"), Value: strPtr("default")},
+					},
+					Gauge: &dto.Gauge{Value: float64Ptr(1)},
+				},
+			},
+		},
+	}
+
+	rms := toResourceMetrics(families, "host1", newSeriesStartTimes())
+	if len(rms) != 2 {
+		t.Fatalf("expected a base ResourceMetrics plus one for the container, got %d: %+v", len(rms), rms)
+	}
+
+	base := rms[0]
+	if len(base.ScopeMetrics[0].Metrics) != 0 {
+		t.Errorf("expected the base resource to carry no metrics, got %+v", base)
+	}
+
+	containerRM := rms[1]
+	attrs := containerRM.Resource.Attributes()
+	want := map[attribute.Key]string{
+		"service.name": "process-exporter",
+		"host.name":    "host1",
+		"container.id": "abc123",
+		"k8s.pod.name": "web-1",
+	}
+	if len(attrs) != len(want) {
+		t.Fatalf("got %d resource attributes, want %d: %+v", len(attrs), len(want), attrs)
+	}
+	for _, a := range attrs {
+		if want[a.Key] != a.Value.AsString() {
+			t.Errorf("resource attribute %s = %q, want %q", a.Key, a.Value.AsString(), want[a.Key])
+		}
+	}
+
+	m := containerRM.ScopeMetrics[0].Metrics[0]
+	gauge := m.Data.(metricdata.Gauge[float64])
+	pointAttrs := gauge.DataPoints[0].Attributes
+	if _, ok := pointAttrs.Value("container_id"); ok {
+		t.Error("container_id should be promoted to a resource attribute, not kept as a point attribute")
+	}
+	if v, ok := pointAttrs.Value("namespace"); !ok || v.AsString() != "default" {
+		t.Errorf("expected namespace (not a fixed resource label) to stay a point attribute, got %v, ok=%v", v, ok)
+	}
+}